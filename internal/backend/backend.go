@@ -0,0 +1,37 @@
+// Package backend defines the storage-provider-agnostic interface ossupload
+// drives its multipart upload, streaming upload and sync logic through.
+// Concrete implementations live in the oss, s3, cos and obs subpackages.
+package backend
+
+import "io"
+
+// Part is one uploaded piece of a multipart upload, as returned by the
+// backend and as recorded in checkpoint files.
+type Part struct {
+	Number int
+	ETag   string
+}
+
+// HeadInfo is the subset of an existing object's metadata ossupload needs to
+// decide whether a local file differs from what's already in the bucket.
+// CRC64 is 0 for backends that don't expose a CRC64 checksum; callers should
+// fall back to ETag in that case.
+type HeadInfo struct {
+	Exists bool
+	Size   int64
+	ETag   string
+	CRC64  uint64
+}
+
+// Uploader is implemented by each supported storage provider. All multipart
+// operations are addressed by the uploadID InitiateMultipart returns, so
+// callers never need to hold onto a provider-specific upload handle.
+type Uploader interface {
+	InitiateMultipart(objectKey string) (uploadID string, err error)
+	UploadPart(objectKey, uploadID string, partNumber int, body io.Reader, size int64) (Part, error)
+	ListParts(objectKey, uploadID string) ([]Part, error)
+	Complete(objectKey, uploadID string, parts []Part) error
+	Abort(objectKey, uploadID string) error
+	PutObject(objectKey string, body io.Reader, size int64) error
+	Head(objectKey string) (HeadInfo, error)
+}
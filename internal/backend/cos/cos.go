@@ -0,0 +1,106 @@
+// Package cos adapts the Tencent Cloud COS SDK to the backend.Uploader interface.
+package cos
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	tencentcos "github.com/tencentyun/cos-go-sdk-v5"
+
+	"github.com/CareyWang/ossupload/internal/backend"
+)
+
+// Backend uploads to a Tencent COS bucket.
+type Backend struct {
+	client *tencentcos.Client
+}
+
+// New creates a COS-backed Uploader. endpoint is the bucket's full access
+// URL, e.g. "https://<bucket>-<appid>.cos.<region>.myqcloud.com".
+func New(endpoint, secretID, secretKey string) (*Backend, error) {
+	bucketURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	client := tencentcos.NewClient(&tencentcos.BaseURL{BucketURL: bucketURL}, &http.Client{
+		Transport: &tencentcos.AuthorizationTransport{SecretID: secretID, SecretKey: secretKey},
+	})
+
+	return &Backend{client: client}, nil
+}
+
+func (b *Backend) InitiateMultipart(objectKey string) (string, error) {
+	result, _, err := b.client.Object.InitiateMultipartUpload(context.Background(), objectKey, nil)
+	if err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+func (b *Backend) UploadPart(objectKey, uploadID string, partNumber int, body io.Reader, size int64) (backend.Part, error) {
+	resp, err := b.client.Object.UploadPart(context.Background(), objectKey, uploadID, partNumber, body,
+		&tencentcos.ObjectUploadPartOptions{ContentLength: size})
+	if err != nil {
+		return backend.Part{}, err
+	}
+	return backend.Part{Number: partNumber, ETag: resp.Header.Get("ETag")}, nil
+}
+
+func (b *Backend) ListParts(objectKey, uploadID string) ([]backend.Part, error) {
+	var parts []backend.Part
+	marker := ""
+	for {
+		result, _, err := b.client.Object.ListParts(context.Background(), objectKey, uploadID,
+			&tencentcos.ObjectListPartsOptions{PartNumberMarker: marker})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range result.Parts {
+			parts = append(parts, backend.Part{Number: p.PartNumber, ETag: p.ETag})
+		}
+
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (b *Backend) Complete(objectKey, uploadID string, parts []backend.Part) error {
+	objects := make([]tencentcos.Object, 0, len(parts))
+	for _, p := range parts {
+		objects = append(objects, tencentcos.Object{PartNumber: p.Number, ETag: p.ETag})
+	}
+	_, _, err := b.client.Object.CompleteMultipartUpload(context.Background(), objectKey, uploadID,
+		&tencentcos.CompleteMultipartUploadOptions{Parts: objects})
+	return err
+}
+
+func (b *Backend) Abort(objectKey, uploadID string) error {
+	_, err := b.client.Object.AbortMultipartUpload(context.Background(), objectKey, uploadID)
+	return err
+}
+
+func (b *Backend) PutObject(objectKey string, body io.Reader, size int64) error {
+	_, err := b.client.Object.Put(context.Background(), objectKey, body, nil)
+	return err
+}
+
+func (b *Backend) Head(objectKey string) (backend.HeadInfo, error) {
+	resp, err := b.client.Object.Head(context.Background(), objectKey, nil)
+	if err != nil {
+		if tencentcos.IsNotFoundError(err) {
+			return backend.HeadInfo{Exists: false}, nil
+		}
+		return backend.HeadInfo{}, err
+	}
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	// COS doesn't expose a CRC64 on plain HEAD; callers fall back to ETag.
+	return backend.HeadInfo{Exists: true, Size: size, ETag: resp.Header.Get("ETag")}, nil
+}
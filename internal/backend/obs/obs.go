@@ -0,0 +1,124 @@
+// Package obs adapts the Huawei Cloud OBS SDK to the backend.Uploader interface.
+package obs
+
+import (
+	"errors"
+	"io"
+
+	huaweiobs "github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
+
+	"github.com/CareyWang/ossupload/internal/backend"
+)
+
+// Backend uploads to a Huawei Cloud OBS bucket.
+type Backend struct {
+	client *huaweiobs.ObsClient
+	bucket string
+}
+
+// New creates an OBS-backed Uploader.
+func New(endpoint, accessKey, secretKey, bucketName string) (*Backend, error) {
+	client, err := huaweiobs.New(accessKey, secretKey, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{client: client, bucket: bucketName}, nil
+}
+
+func (b *Backend) InitiateMultipart(objectKey string) (string, error) {
+	out, err := b.client.InitiateMultipartUpload(&huaweiobs.InitiateMultipartUploadInput{
+		ObjectOperationInput: huaweiobs.ObjectOperationInput{Bucket: b.bucket, Key: objectKey},
+	})
+	if err != nil {
+		return "", err
+	}
+	return out.UploadId, nil
+}
+
+func (b *Backend) UploadPart(objectKey, uploadID string, partNumber int, body io.Reader, size int64) (backend.Part, error) {
+	out, err := b.client.UploadPart(&huaweiobs.UploadPartInput{
+		Bucket:     b.bucket,
+		Key:        objectKey,
+		UploadId:   uploadID,
+		PartNumber: partNumber,
+		PartSize:   size,
+		Body:       body,
+	})
+	if err != nil {
+		return backend.Part{}, err
+	}
+	return backend.Part{Number: out.PartNumber, ETag: out.ETag}, nil
+}
+
+func (b *Backend) ListParts(objectKey, uploadID string) ([]backend.Part, error) {
+	var parts []backend.Part
+	marker := 0
+	for {
+		out, err := b.client.ListParts(&huaweiobs.ListPartsInput{
+			Bucket:           b.bucket,
+			Key:              objectKey,
+			UploadId:         uploadID,
+			PartNumberMarker: marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range out.Parts {
+			parts = append(parts, backend.Part{Number: p.PartNumber, ETag: p.ETag})
+		}
+
+		if !out.IsTruncated {
+			return parts, nil
+		}
+		marker = out.NextPartNumberMarker
+	}
+}
+
+func (b *Backend) Complete(objectKey, uploadID string, parts []backend.Part) error {
+	obsParts := make([]huaweiobs.Part, 0, len(parts))
+	for _, p := range parts {
+		obsParts = append(obsParts, huaweiobs.Part{PartNumber: p.Number, ETag: p.ETag})
+	}
+	_, err := b.client.CompleteMultipartUpload(&huaweiobs.CompleteMultipartUploadInput{
+		Bucket:   b.bucket,
+		Key:      objectKey,
+		UploadId: uploadID,
+		Parts:    obsParts,
+	})
+	return err
+}
+
+func (b *Backend) Abort(objectKey, uploadID string) error {
+	_, err := b.client.AbortMultipartUpload(&huaweiobs.AbortMultipartUploadInput{
+		Bucket:   b.bucket,
+		Key:      objectKey,
+		UploadId: uploadID,
+	})
+	return err
+}
+
+func (b *Backend) PutObject(objectKey string, body io.Reader, size int64) error {
+	_, err := b.client.PutObject(&huaweiobs.PutObjectInput{
+		PutObjectBasicInput: huaweiobs.PutObjectBasicInput{
+			ObjectOperationInput: huaweiobs.ObjectOperationInput{Bucket: b.bucket, Key: objectKey},
+			ContentLength:        size,
+		},
+		Body: body,
+	})
+	return err
+}
+
+func (b *Backend) Head(objectKey string) (backend.HeadInfo, error) {
+	out, err := b.client.GetObjectMetadata(&huaweiobs.GetObjectMetadataInput{Bucket: b.bucket, Key: objectKey})
+	if err != nil {
+		var obsErr huaweiobs.ObsError
+		if errors.As(err, &obsErr) && obsErr.StatusCode == 404 {
+			return backend.HeadInfo{Exists: false}, nil
+		}
+		return backend.HeadInfo{}, err
+	}
+
+	// OBS doesn't expose a CRC64 on plain HEAD; callers fall back to ETag.
+	return backend.HeadInfo{Exists: true, Size: out.ContentLength, ETag: out.ETag}, nil
+}
@@ -0,0 +1,104 @@
+// Package oss adapts the Aliyun OSS SDK to the backend.Uploader interface.
+package oss
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	ossgo "github.com/aliyun/aliyun-oss-go-sdk/oss"
+
+	"github.com/CareyWang/ossupload/internal/backend"
+)
+
+// Backend uploads to an Aliyun OSS bucket.
+type Backend struct {
+	bucket *ossgo.Bucket
+}
+
+// New creates an OSS-backed Uploader for the given bucket.
+func New(endpoint, accessKeyID, accessKeySecret, bucketName string) (*Backend, error) {
+	client, err := ossgo.New(endpoint, accessKeyID, accessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{bucket: bucket}, nil
+}
+
+// imur rebuilds the SDK's multipart upload handle from just the object key
+// and upload ID, which is all InitiateMultipart and checkpoint resume keep
+// around.
+func (b *Backend) imur(objectKey, uploadID string) ossgo.InitiateMultipartUploadResult {
+	return ossgo.InitiateMultipartUploadResult{
+		Bucket:   b.bucket.BucketName,
+		Key:      objectKey,
+		UploadID: uploadID,
+	}
+}
+
+func (b *Backend) InitiateMultipart(objectKey string) (string, error) {
+	imur, err := b.bucket.InitiateMultipartUpload(objectKey)
+	if err != nil {
+		return "", err
+	}
+	return imur.UploadID, nil
+}
+
+func (b *Backend) UploadPart(objectKey, uploadID string, partNumber int, body io.Reader, size int64) (backend.Part, error) {
+	part, err := b.bucket.UploadPart(b.imur(objectKey, uploadID), body, size, partNumber)
+	if err != nil {
+		return backend.Part{}, err
+	}
+	return backend.Part{Number: part.PartNumber, ETag: part.ETag}, nil
+}
+
+func (b *Backend) ListParts(objectKey, uploadID string) ([]backend.Part, error) {
+	result, err := b.bucket.ListUploadedParts(b.imur(objectKey, uploadID))
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]backend.Part, 0, len(result.UploadedParts))
+	for _, p := range result.UploadedParts {
+		parts = append(parts, backend.Part{Number: p.PartNumber, ETag: p.ETag})
+	}
+	return parts, nil
+}
+
+func (b *Backend) Complete(objectKey, uploadID string, parts []backend.Part) error {
+	ossParts := make([]ossgo.UploadPart, 0, len(parts))
+	for _, p := range parts {
+		ossParts = append(ossParts, ossgo.UploadPart{PartNumber: p.Number, ETag: p.ETag})
+	}
+	_, err := b.bucket.CompleteMultipartUpload(b.imur(objectKey, uploadID), ossParts)
+	return err
+}
+
+func (b *Backend) Abort(objectKey, uploadID string) error {
+	return b.bucket.AbortMultipartUpload(b.imur(objectKey, uploadID))
+}
+
+func (b *Backend) PutObject(objectKey string, body io.Reader, size int64) error {
+	return b.bucket.PutObject(objectKey, body)
+}
+
+func (b *Backend) Head(objectKey string) (backend.HeadInfo, error) {
+	header, err := b.bucket.GetObjectDetailedMeta(objectKey)
+	if err != nil {
+		var svcErr ossgo.ServiceError
+		if errors.As(err, &svcErr) && svcErr.StatusCode == 404 {
+			return backend.HeadInfo{Exists: false}, nil
+		}
+		return backend.HeadInfo{}, err
+	}
+
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	crc, _ := strconv.ParseUint(header.Get("X-Oss-Hash-Crc64ecma"), 10, 64)
+	return backend.HeadInfo{Exists: true, Size: size, ETag: header.Get("Etag"), CRC64: crc}, nil
+}
@@ -0,0 +1,155 @@
+// Package s3 adapts aws-sdk-go-v2's S3 client to the backend.Uploader interface.
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/CareyWang/ossupload/internal/backend"
+)
+
+// Backend uploads to an S3 (or S3-compatible) bucket.
+type Backend struct {
+	client *awss3.Client
+	bucket string
+}
+
+// New creates an S3-backed Uploader. endpoint may be empty to use AWS's
+// regional endpoint, or set to point at an S3-compatible service.
+func New(region, endpoint, accessKeyID, accessKeySecret, bucketName string) (*Backend, error) {
+	client := awss3.New(awss3.Options{
+		Region:       region,
+		Credentials:  credentials.NewStaticCredentialsProvider(accessKeyID, accessKeySecret, ""),
+		UsePathStyle: endpoint != "",
+		BaseEndpoint: nonEmptyPtr(endpoint),
+	})
+
+	return &Backend{client: client, bucket: bucketName}, nil
+}
+
+func nonEmptyPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (b *Backend) InitiateMultipart(objectKey string) (string, error) {
+	out, err := b.client.CreateMultipartUpload(context.Background(), &awss3.CreateMultipartUploadInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+func (b *Backend) UploadPart(objectKey, uploadID string, partNumber int, body io.Reader, size int64) (backend.Part, error) {
+	out, err := b.client.UploadPart(context.Background(), &awss3.UploadPartInput{
+		Bucket:        aws.String(b.bucket),
+		Key:           aws.String(objectKey),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(int32(partNumber)),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return backend.Part{}, err
+	}
+	return backend.Part{Number: partNumber, ETag: aws.ToString(out.ETag)}, nil
+}
+
+func (b *Backend) ListParts(objectKey, uploadID string) ([]backend.Part, error) {
+	var parts []backend.Part
+	marker := ""
+	for {
+		out, err := b.client.ListParts(context.Background(), &awss3.ListPartsInput{
+			Bucket:           aws.String(b.bucket),
+			Key:              aws.String(objectKey),
+			UploadId:         aws.String(uploadID),
+			PartNumberMarker: aws.String(marker),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range out.Parts {
+			parts = append(parts, backend.Part{Number: int(aws.ToInt32(p.PartNumber)), ETag: aws.ToString(p.ETag)})
+		}
+
+		if !aws.ToBool(out.IsTruncated) {
+			return parts, nil
+		}
+		marker = aws.ToString(out.NextPartNumberMarker)
+	}
+}
+
+func (b *Backend) Complete(objectKey, uploadID string, parts []backend.Part) error {
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		completed = append(completed, types.CompletedPart{
+			PartNumber: aws.Int32(int32(p.Number)),
+			ETag:       aws.String(p.ETag),
+		})
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		return aws.ToInt32(completed[i].PartNumber) < aws.ToInt32(completed[j].PartNumber)
+	})
+
+	_, err := b.client.CompleteMultipartUpload(context.Background(), &awss3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(b.bucket),
+		Key:             aws.String(objectKey),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	return err
+}
+
+func (b *Backend) Abort(objectKey, uploadID string) error {
+	_, err := b.client.AbortMultipartUpload(context.Background(), &awss3.AbortMultipartUploadInput{
+		Bucket:   aws.String(b.bucket),
+		Key:      aws.String(objectKey),
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
+
+func (b *Backend) PutObject(objectKey string, body io.Reader, size int64) error {
+	_, err := b.client.PutObject(context.Background(), &awss3.PutObjectInput{
+		Bucket:        aws.String(b.bucket),
+		Key:           aws.String(objectKey),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+	return err
+}
+
+func (b *Backend) Head(objectKey string) (backend.HeadInfo, error) {
+	out, err := b.client.HeadObject(context.Background(), &awss3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotFound" {
+			return backend.HeadInfo{Exists: false}, nil
+		}
+		return backend.HeadInfo{}, err
+	}
+
+	// S3 doesn't expose a CRC64; callers fall back to ETag for change detection.
+	return backend.HeadInfo{
+		Exists: true,
+		Size:   aws.ToInt64(out.ContentLength),
+		ETag:   aws.ToString(out.ETag),
+	}, nil
+}
@@ -1,63 +1,201 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"hash"
+	"hash/crc64"
 	"io"
+	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/CareyWang/ossupload/internal/backend"
+	"github.com/CareyWang/ossupload/internal/backend/cos"
+	"github.com/CareyWang/ossupload/internal/backend/obs"
+	"github.com/CareyWang/ossupload/internal/backend/oss"
+	"github.com/CareyWang/ossupload/internal/backend/s3"
 )
 
 var (
 	accessKeyID     string
 	accessKeySecret string
 	endpoint        string
+	region          string
+	backendName     string
 	bucketName      string
 	objectName      string
 	filePath        string
+	parallel        int
+	checkpointPath  string
+	partSizeFlag    string
+	maxParts        int
+	progressMode    string
+	metricsAddr     string
 )
 
-// 分片大小为1GB
-const partSize = 1 << 30 // 1GB
+// maxUploadRetries 是单个分片上传失败后的最大重试次数。
+const maxUploadRetries = 5
 
-// init initializes accessKeyID, accessKeySecret, endpoint, bucketName, objectName, and filePath
-// by retrieving the values from the environment variables, command line arguments, and flags.
+// OSS 对单次分片上传的限制：分片数不能超过 10000。
+const ossMaxParts = 10000
+
+// minPartSize and maxPartSize bound the part size OSS will accept, regardless
+// of what -part-size or the adaptive calculation produce.
+const (
+	minPartSize = 5 << 20 // 5MiB
+	maxPartSize = 5 << 30 // 5GiB
+)
+
+// simpleUploadThreshold is the file size above which multipartUpload is used
+// instead of a single PutObject call.
+const simpleUploadThreshold = 1 << 30 // 1GB
+
+// stdinPath is the -file value that selects streaming upload from stdin.
+const stdinPath = "-"
+
+// init registers the flags for the default upload mode and reads the
+// credentials from the environment. flag.Parse is deferred to main so the
+// "sync" subcommand can be dispatched before these flags are parsed.
 func init() {
 	accessKeyID = os.Getenv("ACCESS_KEY")
 	accessKeySecret = os.Getenv("ACCESS_SECRET")
-	flag.StringVar(&endpoint, "endpoint", "", "OSS endpoint")
+	flag.StringVar(&backendName, "backend", "oss", "storage backend: oss, s3, cos or obs")
+	flag.StringVar(&endpoint, "endpoint", "", "storage endpoint (for cos, the bucket's full access URL)")
+	flag.StringVar(&region, "region", "", "region, required by the s3 backend")
 	flag.StringVar(&bucketName, "bucket", "", "Bucket name")
 	flag.StringVar(&objectName, "object", "", "Object name")
 	flag.StringVar(&filePath, "file", "", "File path")
-	flag.Parse()
+	flag.IntVar(&parallel, "parallel", 1, "number of concurrent workers for multipart upload (1-100)")
+	flag.StringVar(&checkpointPath, "checkpoint", "", "checkpoint file path, enables resumable multipart upload")
+	flag.StringVar(&partSizeFlag, "part-size", "", "part size for multipart upload, e.g. 100MB (default: adaptive, between 5MB and 5GB)")
+	flag.IntVar(&maxParts, "max-parts", ossMaxParts, "maximum number of parts for multipart upload (OSS allows at most 10000)")
+	flag.StringVar(&progressMode, "progress", progressTTY, "progress reporter: tty, json, none or prom")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "address for the Prometheus metrics endpoint, e.g. :9100 (required when -progress=prom)")
+}
+
+// backendCredentialEnv names the backend-specific environment variables
+// newUploader falls back to when the common ACCESS_KEY/ACCESS_SECRET pair
+// isn't set, so each provider's own credential convention can be used
+// without having to rename it to the common pair.
+var backendCredentialEnv = map[string][2]string{
+	"oss": {"OSS_ACCESS_KEY", "OSS_ACCESS_SECRET"},
+	"s3":  {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+	"cos": {"COS_SECRET_ID", "COS_SECRET_KEY"},
+	"obs": {"OBS_ACCESS_KEY", "OBS_ACCESS_SECRET"},
+}
+
+// backendCredentials resolves the access key/secret for the given backend:
+// the common ACCESS_KEY/ACCESS_SECRET env vars win if set, otherwise it falls
+// back to that backend's own credential env vars from backendCredentialEnv.
+func backendCredentials(backendName string) (ak, sk string) {
+	ak, sk = accessKeyID, accessKeySecret
+	envNames, ok := backendCredentialEnv[backendName]
+	if !ok {
+		return ak, sk
+	}
+	if ak == "" {
+		ak = os.Getenv(envNames[0])
+	}
+	if sk == "" {
+		sk = os.Getenv(envNames[1])
+	}
+	return ak, sk
+}
+
+// newUploader builds the backend.Uploader selected by -backend, wiring up
+// each provider's SDK client from the shared -endpoint/-bucket/-region flags
+// and credentials sourced from ACCESS_KEY/ACCESS_SECRET, falling back to each
+// backend's own credential env vars (see backendCredentialEnv) when unset.
+func newUploader() (backend.Uploader, error) {
+	name := backendName
+	if name == "" {
+		name = "oss"
+	}
+	ak, sk := backendCredentials(name)
+
+	switch name {
+	case "oss":
+		return oss.New(endpoint, ak, sk, bucketName)
+	case "s3":
+		return s3.New(region, endpoint, ak, sk, bucketName)
+	case "cos":
+		return cos.New(endpoint, ak, sk)
+	case "obs":
+		return obs.New(endpoint, ak, sk, bucketName)
+	default:
+		return nil, fmt.Errorf("unknown -backend %q: expected oss, s3, cos or obs", backendName)
+	}
 }
 
 // main is the entry point of the program.
 //
-// It initializes the program, checks the parameters, creates an OSSClient
-// instance, gets the bucket, uploads the file, and prints a success message.
-// It exits with -1 if any error occurs.
+// It initializes the program, checks the parameters, creates the storage
+// backend selected by -backend, uploads the file, and prints a success
+// message. It exits with -1 if any error occurs.
+//
+// Run as "ossupload sync ..." it instead mirrors a local directory into a
+// bucket; see runSync. Run as "ossupload copy ..." it performs a server-side
+// multipart copy between two buckets; see runCopy.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		runSync(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "copy" {
+		runCopy(os.Args[2:])
+		return
+	}
+
+	flag.Parse()
+
 	// Check parameters
 	if accessKeyID == "" || accessKeySecret == "" || bucketName == "" || objectName == "" || filePath == "" {
 		fmt.Println("missing parameters")
 		os.Exit(-1)
 	}
+	if parallel < 1 || parallel > 100 {
+		fmt.Println("error: -parallel must be between 1 and 100")
+		os.Exit(-1)
+	}
+	if maxParts < 1 || maxParts > ossMaxParts {
+		fmt.Printf("error: -max-parts must be between 1 and %d\n", ossMaxParts)
+		os.Exit(-1)
+	}
+	userPartSize, err := parsePartSizeFlag(partSizeFlag)
+	if err != nil {
+		fmt.Println("error: ", err)
+		os.Exit(-1)
+	}
 
-	// Create an OSSClient instance.
-	client, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	up, err := newUploader()
 	if err != nil {
 		fmt.Println("error: ", err)
 		os.Exit(-1)
 	}
 
-	// Get the bucket.
-	bucket, err := client.Bucket(bucketName)
+	reporter, err := newProgressReporter(progressMode, metricsAddr)
 	if err != nil {
 		fmt.Println("error: ", err)
 		os.Exit(-1)
 	}
+	defer reporter.close()
+
+	// 从标准输入流式上传，文件大小未知，不能 os.Stat 或 seek。
+	if filePath == stdinPath {
+		if err = streamUpload(up, os.Stdin, objectName, userPartSize, reporter); err != nil {
+			fmt.Println("error: ", err)
+			os.Exit(-1)
+		}
+		fmt.Println("upload success!")
+		return
+	}
 
 	// Upload the file.
 	stat, err := os.Stat(filePath)
@@ -70,10 +208,10 @@ func main() {
 
 	// 判断文件大小，如果大于1G，则使用分片上传，否则使用简单上传。
 	fileSize := stat.Size()
-	if fileSize > partSize {
-		err = multipartUpload(bucket, filePath, objectName)
+	if fileSize > simpleUploadThreshold {
+		err = multipartUpload(up, filePath, objectName, userPartSize, reporter)
 	} else {
-		err = simpleUpload(bucket, filePath, objectName)
+		err = simpleUpload(up, filePath, objectName)
 	}
 	if err != nil {
 		fmt.Println("error: ", err)
@@ -83,92 +221,590 @@ func main() {
 	fmt.Println("upload success!")
 }
 
-// 定义进度条监听器。
-type OssProgressListener struct {
-}
-
-// 定义进度变更事件处理函数。
-func (listener *OssProgressListener) ProgressChanged(event *oss.ProgressEvent) {
-	switch event.EventType {
-	case oss.TransferStartedEvent:
-		fmt.Printf("started, consumed bytes: %d, total bytes: %d.\n",
-			event.ConsumedBytes, event.TotalBytes)
-	case oss.TransferDataEvent:
-		fmt.Printf("\ruploading consumed bytes: %d, total bytes: %d, %d%%.",
-			event.ConsumedBytes, event.TotalBytes, event.ConsumedBytes*100/event.TotalBytes)
-	case oss.TransferCompletedEvent:
-		fmt.Printf("\ncompleted, consumed bytes: %d, total bytes: %d.\n",
-			event.ConsumedBytes, event.TotalBytes)
-	case oss.TransferFailedEvent:
-		fmt.Printf("\nfailed, consumed bytes: %d, total bytes: %d.\n\n",
-			event.ConsumedBytes, event.TotalBytes)
-	default:
+// simpleUpload uploads a file to the backend in a single PutObject call.
+//
+// up: the backend.Uploader to upload to.
+// filePath: a string representing the local file path to upload from.
+// objPath: a string representing the object path to upload to.
+// Returns an error if the upload fails.
+func simpleUpload(up backend.Uploader, filePath, objPath string) error {
+	fd, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	stat, err := fd.Stat()
+	if err != nil {
+		return err
 	}
+
+	return up.PutObject(objPath, fd, stat.Size())
 }
 
-// simpleUpload uploads a file to an OSS bucket using the specified bucket object and file paths.
-//
-// bucket: An *oss.Bucket object representing the OSS bucket to upload to.
-// filePath: A string representing the local file path to upload from.
-// objPath: A string representing the object path to upload to in the OSS bucket.
-// Returns an error if the upload fails.
-func simpleUpload(bucket *oss.Bucket, filePath, objPath string) error {
-	return bucket.PutObjectFromFile(objPath, filePath, oss.Progress(&OssProgressListener{}))
+// checkpointPart is one completed part recorded in the checkpoint file.
+type checkpointPart struct {
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+	CRC64      uint64 `json:"crc64"`
+}
+
+// uploadCheckpoint is the on-disk state of an in-progress multipart upload. It is
+// written after every completed part so the upload can resume from where it left
+// off if the process is interrupted.
+type uploadCheckpoint struct {
+	UploadID string           `json:"upload_id"`
+	FilePath string           `json:"file_path"`
+	FileSize int64            `json:"file_size"`
+	ModTime  time.Time        `json:"mod_time"`
+	PartSize int64            `json:"part_size"`
+	Parts    []checkpointPart `json:"parts"`
+
+	mu sync.Mutex `json:"-"`
 }
 
-// multipartUpload uploads a large file to an OSS bucket using multipart upload.
+// loadCheckpoint reads and decodes a checkpoint file. A missing file is not an
+// error; it simply means there is nothing to resume.
+func loadCheckpoint(path string) (*uploadCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cp := &uploadCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// matches reports whether the checkpoint was taken against the same local file,
+// i.e. the size and modification time have not changed since.
+func (cp *uploadCheckpoint) matches(stat os.FileInfo) bool {
+	return cp.FileSize == stat.Size() && cp.ModTime.Equal(stat.ModTime())
+}
+
+// completedNumbers returns the set of part numbers already recorded as uploaded.
+func (cp *uploadCheckpoint) completedNumbers() map[int]checkpointPart {
+	done := make(map[int]checkpointPart, len(cp.Parts))
+	for _, p := range cp.Parts {
+		done[p.PartNumber] = p
+	}
+	return done
+}
+
+// addPart records a completed part and persists the checkpoint to disk.
+func (cp *uploadCheckpoint) addPart(path string, part checkpointPart) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.Parts = append(cp.Parts, part)
+	return cp.save(path)
+}
+
+// save atomically rewrites the checkpoint file by writing to a temporary file
+// in the same directory and renaming it into place.
+func (cp *uploadCheckpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removeCheckpoint deletes the checkpoint file once the upload has completed.
+func removeCheckpoint(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		fmt.Println("warning: failed to remove checkpoint file:", err)
+	}
+}
+
+// fileChunk describes one part of a file to be uploaded.
+type fileChunk struct {
+	Number int
+	Offset int64
+	Size   int64
+}
+
+// splitFileByPartSize divides a file of size fileSize into parts of partSize
+// bytes each (the last part may be smaller), numbered from 1.
+func splitFileByPartSize(fileSize, partSize int64) []fileChunk {
+	if fileSize == 0 {
+		return []fileChunk{{Number: 1, Offset: 0, Size: 0}}
+	}
+
+	var chunks []fileChunk
+	number := 1
+	for offset := int64(0); offset < fileSize; offset += partSize {
+		size := partSize
+		if remaining := fileSize - offset; remaining < size {
+			size = remaining
+		}
+		chunks = append(chunks, fileChunk{Number: number, Offset: offset, Size: size})
+		number++
+	}
+	return chunks
+}
+
+// partJob describes a single part still left to upload.
+type partJob struct {
+	Number int
+	Offset int64
+	Size   int64
+}
+
+// partOutcome is the result of uploading one part, fed back to the coordinator
+// goroutine so it can update the checkpoint and assemble the final part list.
+type partOutcome struct {
+	Number int
+	Part   backend.Part
+	CRC64  uint64
+	Err    error
+}
+
+// multipartUpload uploads a large file to the backend using multipart upload.
+//
+// When -parallel is greater than 1, parts are uploaded concurrently by a pool of
+// worker goroutines, each reading its own io.SectionReader over the file so
+// workers never share a seek position. When -checkpoint is set, progress is
+// persisted after every part so an interrupted upload can resume instead of
+// starting over.
 //
-// bucket: an OSS bucket object that will receive the uploaded file parts.
+// up: the backend.Uploader that will receive the uploaded file parts.
 // filePath: a string of the local file path to be uploaded.
 // objPath: a string of the object path to be created in the bucket.
+// userPartSize: an explicit part size from -part-size, or 0 to select one
+// adaptively based on the file size and -max-parts.
+// reporter receives progress events as parts are uploaded; see -progress.
 //
 // Returns an error if the upload fails.
-func multipartUpload(bucket *oss.Bucket, filePath, objPath string) error {
-	stat, _ := os.Stat(filePath)
-	splitParts := stat.Size() / partSize
-	if stat.Size()%partSize != 0 {
-		splitParts++
-	}
-
-	chunks, err := oss.SplitFileByPartNum(filePath, int(splitParts))
+func multipartUpload(up backend.Uploader, filePath, objPath string, userPartSize int64, reporter progressReporter) error {
+	stat, err := os.Stat(filePath)
 	if err != nil {
 		return err
 	}
 
+	partSz := choosePartSize(userPartSize, stat.Size())
+	chunks := splitFileByPartSize(stat.Size(), partSz)
+
 	fd, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 	defer fd.Close()
 
-	// 步骤1：初始化一个分片上传事件。
-	options := []oss.Option{}
-	imur, err := bucket.InitiateMultipartUpload(objectName, options...)
+	uploadID, cp, resumed, err := initiateOrResumeUpload(up, filePath, objPath, stat, partSz, reporter)
 	if err != nil {
 		return err
 	}
 
-	// 步骤2：上传分片。
-	var parts []oss.UploadPart
-	fmt.Println("start upload parts, total: ", len(chunks))
+	done := map[int]checkpointPart{}
+	if resumed {
+		done = cp.completedNumbers()
+	}
+
+	reporter.start(len(chunks), stat.Size(), parallel)
+
+	jobs := make(chan partJob, len(chunks))
+	pending := 0
+	for _, chunk := range chunks {
+		if existing, ok := done[chunk.Number]; ok {
+			reporter.note(fmt.Sprintf("skip part %d, already uploaded (etag %s)", chunk.Number, existing.ETag))
+			reporter.partSkipped(chunk.Number, chunk.Size)
+			continue
+		}
+		jobs <- partJob{Number: chunk.Number, Offset: chunk.Offset, Size: chunk.Size}
+		pending++
+	}
+	close(jobs)
+
+	reporter.note(fmt.Sprintf("start upload parts, total: %d, pending: %d, parallel: %d", len(chunks), pending, parallel))
+
+	results := make(chan partOutcome, pending)
+	var wg sync.WaitGroup
+	workers := parallel
+	if workers > pending && pending > 0 {
+		workers = pending
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uploadPartWorker(up, objPath, uploadID, fd, jobs, results, reporter)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	parts := map[int]backend.Part{}
+	for _, p := range cp.Parts {
+		parts[p.PartNumber] = backend.Part{Number: p.PartNumber, ETag: p.ETag}
+	}
+
+	var firstErr error
+	for outcome := range results {
+		if outcome.Err != nil {
+			if firstErr == nil {
+				firstErr = outcome.Err
+			}
+			continue
+		}
+
+		parts[outcome.Number] = outcome.Part
+
+		if checkpointPath != "" {
+			record := checkpointPart{PartNumber: outcome.Number, ETag: outcome.Part.ETag, CRC64: outcome.CRC64}
+			if err := cp.addPart(checkpointPath, record); err != nil {
+				reporter.note(fmt.Sprintf("warning: failed to persist checkpoint: %v", err))
+			}
+		}
+	}
+
+	reporter.finish()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	orderedParts := make([]backend.Part, 0, len(parts))
 	for _, chunk := range chunks {
-		fmt.Printf("upload part %d\n", chunk.Number)
+		orderedParts = append(orderedParts, parts[chunk.Number])
+	}
 
-		// fd.Seek(chunk.Offset, os.SEEK_SET)
-		fd.Seek(chunk.Offset, io.SeekStart)
-		// 调用UploadPart方法上传每个分片。
-		part, err := bucket.UploadPart(imur, fd, chunk.Size, chunk.Number, oss.Progress(&OssProgressListener{}))
+	// 完成分片上传。
+	if err := up.Complete(objPath, uploadID, orderedParts); err != nil {
+		return err
+	}
+
+	removeCheckpoint(checkpointPath)
+	return nil
+}
+
+// initiateOrResumeUpload either starts a brand new multipart upload, or, when a
+// matching checkpoint file is found, resumes the one recorded in it by
+// reconciling its part list against what the backend reports via ListParts.
+func initiateOrResumeUpload(up backend.Uploader, filePath, objPath string, stat os.FileInfo, partSz int64, reporter progressReporter) (string, *uploadCheckpoint, bool, error) {
+	if checkpointPath != "" {
+		cp, err := loadCheckpoint(checkpointPath)
 		if err != nil {
-			return err
+			return "", nil, false, err
+		}
+		if cp != nil && cp.matches(stat) && cp.PartSize == partSz {
+			uploaded, err := up.ListParts(objPath, cp.UploadID)
+			if err != nil {
+				return "", nil, false, err
+			}
+
+			remote := map[int]string{}
+			for _, p := range uploaded {
+				remote[p.Number] = p.ETag
+			}
+
+			reconciled := cp.Parts[:0]
+			for _, p := range cp.Parts {
+				if etag, ok := remote[p.PartNumber]; ok && etag == p.ETag {
+					reconciled = append(reconciled, p)
+				}
+			}
+			cp.Parts = reconciled
+
+			reporter.note(fmt.Sprintf("resuming upload %s, %d parts already uploaded", cp.UploadID, len(cp.Parts)))
+			return cp.UploadID, cp, true, nil
+		}
+	}
+
+	uploadID, err := up.InitiateMultipart(objPath)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	cp := &uploadCheckpoint{
+		UploadID: uploadID,
+		FilePath: filePath,
+		FileSize: stat.Size(),
+		ModTime:  stat.ModTime(),
+		PartSize: partSz,
+	}
+	if checkpointPath != "" {
+		if err := cp.save(checkpointPath); err != nil {
+			return "", nil, false, err
+		}
+	}
+
+	return uploadID, cp, false, nil
+}
+
+// uploadPartWorker pulls part jobs off jobs until the channel is closed,
+// uploading each with its own io.SectionReader over fd so concurrent workers
+// never interfere with each other's read position, and publishes the outcome
+// on results.
+func uploadPartWorker(up backend.Uploader, objectKey, uploadID string, fd *os.File, jobs <-chan partJob, results chan<- partOutcome, reporter progressReporter) {
+	for job := range jobs {
+		section := io.NewSectionReader(fd, job.Offset, job.Size)
+		part, crc, err := uploadPartWithRetry(up, objectKey, uploadID, section, job, reporter)
+		results <- partOutcome{Number: job.Number, Part: part, CRC64: crc, Err: err}
+	}
+}
+
+// uploadPartWithRetry uploads a single part, retrying transient failures with
+// exponential backoff, and returns the resulting part along with the CRC64 of
+// the bytes that were sent.
+func uploadPartWithRetry(up backend.Uploader, objectKey, uploadID string, section *io.SectionReader, job partJob, reporter progressReporter) (backend.Part, uint64, error) {
+	crc := newCRC64Writer()
+	var lastErr error
+
+	reporter.partStarted(job.Number, job.Size)
+
+	for attempt := 0; attempt <= maxUploadRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second
+			reporter.note(fmt.Sprintf("retrying part %d (attempt %d) after %s: %v", job.Number, attempt, backoff, lastErr))
+			time.Sleep(backoff)
+			reporter.partRetrying(job.Number, job.Size)
+		}
+
+		if _, err := section.Seek(0, io.SeekStart); err != nil {
+			reporter.partCompleted(job.Number, job.Size, err)
+			return backend.Part{}, 0, err
+		}
+		crc.Reset()
+		reader := io.TeeReader(section, crc)
+		counted := &progressCountingReader{r: reader, size: job.Size, onProgress: func(consumed, size int64) {
+			reporter.partProgress(job.Number, consumed, size)
+		}}
+
+		part, err := up.UploadPart(objectKey, uploadID, job.Number, counted, job.Size)
+		if err == nil {
+			reporter.partCompleted(job.Number, job.Size, nil)
+			return part, crc.Sum64(), nil
+		}
+		lastErr = err
+	}
+
+	err := fmt.Errorf("part %d: giving up after %d attempts: %w", job.Number, maxUploadRetries+1, lastErr)
+	reporter.partCompleted(job.Number, job.Size, err)
+	return backend.Part{}, 0, err
+}
+
+// ossCRC64Table is the polynomial OSS uses for its x-oss-hash-crc64ecma header.
+var ossCRC64Table = crc64.MakeTable(crc64.ECMA)
+
+// newCRC64Writer returns a hash.Hash64 matching OSS's CRC64 so each part's
+// checksum can be recorded alongside its ETag in the checkpoint.
+func newCRC64Writer() hash.Hash64 {
+	return crc64.New(ossCRC64Table)
+}
+
+// parsePartSizeFlag parses the human-readable -part-size flag (e.g. "100MB")
+// into a byte count. An empty string means "let choosePartSize decide".
+func parsePartSizeFlag(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			numPart := strings.TrimSuffix(upper, u.suffix)
+			value, err := strconv.ParseFloat(numPart, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid -part-size %q: %w", s, err)
+			}
+			size := int64(value * float64(u.factor))
+			if size <= 0 {
+				return 0, fmt.Errorf("invalid -part-size %q: must be positive", s)
+			}
+			return size, nil
 		}
-		parts = append(parts, part)
 	}
 
-	// 步骤2：完成分片上传。
-	_, err = bucket.CompleteMultipartUpload(imur, parts)
+	return 0, fmt.Errorf("invalid -part-size %q: expected a number followed by B, KB, MB, GB or TB", s)
+}
+
+// choosePartSize returns the part size to split a file of the given size
+// into. An explicit userPartSize always wins (clamped to what OSS accepts);
+// otherwise it picks the smallest size, in [minPartSize, maxPartSize], that
+// keeps the part count within -max-parts.
+func choosePartSize(userPartSize, fileSize int64) int64 {
+	if userPartSize > 0 {
+		return clampPartSize(userPartSize)
+	}
+
+	size := fileSize / int64(maxParts)
+	if fileSize%int64(maxParts) != 0 {
+		size++
+	}
+	return clampPartSize(size)
+}
+
+// clampPartSize keeps a part size within what OSS accepts: 5MiB to 5GiB.
+func clampPartSize(size int64) int64 {
+	if size < minPartSize {
+		return minPartSize
+	}
+	if size > maxPartSize {
+		return maxPartSize
+	}
+	return size
+}
+
+// streamJob is one buffer of data read from a non-seekable source, ready to
+// be uploaded as a single part.
+type streamJob struct {
+	Number int
+	Data   []byte
+}
+
+// streamUpload uploads an io.Reader that cannot be seeked or os.Stat'd (e.g.
+// stdin fed by a pipe) using multipart upload. It reads fixed-size chunks
+// into a bounded ring of reusable buffers and uploads them concurrently,
+// without needing the file's total size up front.
+// reporter receives progress events as parts are uploaded; see -progress.
+func streamUpload(up backend.Uploader, reader io.Reader, objPath string, userPartSize int64, reporter progressReporter) error {
+	partSz := userPartSize
+	if partSz == 0 {
+		partSz = minPartSize
+	}
+	partSz = clampPartSize(partSz)
+
+	uploadID, err := up.InitiateMultipart(objPath)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// The total size is unknown up front for a stream, so report 0 parts/bytes.
+	reporter.start(0, 0, parallel)
+
+	ringSize := parallel + 1
+	bufPool := make(chan []byte, ringSize)
+	for i := 0; i < ringSize; i++ {
+		bufPool <- make([]byte, partSz)
+	}
+
+	jobs := make(chan streamJob, ringSize)
+	results := make(chan partOutcome, ringSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			streamPartWorker(up, objPath, uploadID, jobs, results, bufPool, reporter)
+		}()
+	}
+
+	readErrCh := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		readErrCh <- readStreamInto(reader, bufPool, jobs, partSz)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var parts []backend.Part
+	var firstErr error
+	for outcome := range results {
+		if outcome.Err != nil {
+			if firstErr == nil {
+				firstErr = outcome.Err
+			}
+			continue
+		}
+		parts = append(parts, outcome.Part)
+	}
+
+	reporter.finish()
+
+	if err := <-readErrCh; err != nil {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		_ = up.Abort(objPath, uploadID)
+		return firstErr
+	}
+
+	sortUploadParts(parts)
+	return up.Complete(objPath, uploadID, parts)
+}
+
+// readStreamInto reads partSz-sized chunks from reader into buffers borrowed
+// from bufPool and pushes one streamJob per chunk, numbering parts from 1.
+// It stops cleanly on io.EOF/io.ErrUnexpectedEOF and returns any other error.
+func readStreamInto(reader io.Reader, bufPool chan []byte, jobs chan<- streamJob, partSz int64) error {
+	for partNumber := 1; ; partNumber++ {
+		if partNumber > maxParts {
+			return fmt.Errorf("stream exceeded %d parts at %d bytes per part; rerun with a larger -part-size", maxParts, partSz)
+		}
+
+		buf := <-bufPool
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			jobs <- streamJob{Number: partNumber, Data: buf[:n]}
+		} else {
+			bufPool <- buf
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// streamPartWorker uploads streamJobs until jobs is closed, returning each
+// buffer to bufPool once the part has been sent so it can be reused.
+func streamPartWorker(up backend.Uploader, objectKey, uploadID string, jobs <-chan streamJob, results chan<- partOutcome, bufPool chan []byte, reporter progressReporter) {
+	for job := range jobs {
+		size := int64(len(job.Data))
+		reporter.partStarted(job.Number, size)
+		counted := &progressCountingReader{r: bytes.NewReader(job.Data), size: size, onProgress: func(consumed, size int64) {
+			reporter.partProgress(job.Number, consumed, size)
+		}}
+		part, err := up.UploadPart(objectKey, uploadID, job.Number, counted, size)
+		reporter.partCompleted(job.Number, size, err)
+		results <- partOutcome{Number: job.Number, Part: part, Err: err}
+		bufPool <- job.Data[:cap(job.Data)]
+	}
+}
+
+// sortUploadParts orders parts by part number, as Complete requires.
+func sortUploadParts(parts []backend.Part) {
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CareyWang/ossupload/internal/backend"
+)
+
+func TestSplitFileByPartSize(t *testing.T) {
+	chunks := splitFileByPartSize(0, 5<<20)
+	if len(chunks) != 1 || chunks[0].Size != 0 {
+		t.Fatalf("zero-size file should produce a single empty chunk, got %+v", chunks)
+	}
+
+	chunks = splitFileByPartSize(10<<20, 5<<20)
+	want := []fileChunk{
+		{Number: 1, Offset: 0, Size: 5 << 20},
+		{Number: 2, Offset: 5 << 20, Size: 5 << 20},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("exact division: got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, c := range chunks {
+		if c != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+
+	chunks = splitFileByPartSize(12<<20, 5<<20)
+	if len(chunks) != 3 {
+		t.Fatalf("remainder: got %d chunks, want 3", len(chunks))
+	}
+	if last := chunks[2]; last.Size != 2<<20 || last.Offset != 10<<20 {
+		t.Errorf("last chunk should be the 2MiB remainder at offset 10MiB, got %+v", last)
+	}
+
+	var total int64
+	for _, c := range chunks {
+		total += c.Size
+	}
+	if total != 12<<20 {
+		t.Errorf("chunk sizes should sum to the file size: got %d, want %d", total, 12<<20)
+	}
+}
+
+func TestUploadCheckpointMatches(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.bin")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := &uploadCheckpoint{FileSize: stat.Size(), ModTime: stat.ModTime()}
+	if !cp.matches(stat) {
+		t.Error("checkpoint should match the file it was taken from")
+	}
+
+	cp.FileSize++
+	if cp.matches(stat) {
+		t.Error("checkpoint should not match once the file size differs")
+	}
+
+	cp.FileSize = stat.Size()
+	cp.ModTime = stat.ModTime().Add(-time.Hour)
+	if cp.matches(stat) {
+		t.Error("checkpoint should not match once the mod time differs")
+	}
+}
+
+func TestUploadCheckpointCompletedNumbers(t *testing.T) {
+	cp := &uploadCheckpoint{Parts: []checkpointPart{
+		{PartNumber: 2, ETag: "etag-2"},
+		{PartNumber: 1, ETag: "etag-1"},
+	}}
+	done := cp.completedNumbers()
+	if len(done) != 2 {
+		t.Fatalf("got %d completed parts, want 2", len(done))
+	}
+	if done[1].ETag != "etag-1" || done[2].ETag != "etag-2" {
+		t.Errorf("completedNumbers returned unexpected etags: %+v", done)
+	}
+}
+
+// fakeUploader is a minimal backend.Uploader stub for exercising checkpoint
+// reconciliation in initiateOrResumeUpload without a real storage backend.
+type fakeUploader struct {
+	listed  []backend.Part
+	listErr error
+}
+
+func (f *fakeUploader) InitiateMultipart(objectKey string) (string, error) {
+	return "new-upload-id", nil
+}
+func (f *fakeUploader) UploadPart(objectKey, uploadID string, partNumber int, body io.Reader, size int64) (backend.Part, error) {
+	return backend.Part{}, nil
+}
+func (f *fakeUploader) ListParts(objectKey, uploadID string) ([]backend.Part, error) {
+	return f.listed, f.listErr
+}
+func (f *fakeUploader) Complete(objectKey, uploadID string, parts []backend.Part) error { return nil }
+func (f *fakeUploader) Abort(objectKey, uploadID string) error                          { return nil }
+func (f *fakeUploader) PutObject(objectKey string, body io.Reader, size int64) error    { return nil }
+func (f *fakeUploader) Head(objectKey string) (backend.HeadInfo, error) {
+	return backend.HeadInfo{}, nil
+}
+
+func TestInitiateOrResumeUploadReconcilesAgainstRemoteParts(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "f.bin")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpPath := filepath.Join(dir, "checkpoint.json")
+	existing := &uploadCheckpoint{
+		UploadID: "resumed-upload-id",
+		FilePath: filePath,
+		FileSize: stat.Size(),
+		ModTime:  stat.ModTime(),
+		PartSize: 5 << 20,
+		Parts: []checkpointPart{
+			{PartNumber: 1, ETag: "etag-1", CRC64: 1},
+			{PartNumber: 2, ETag: "stale-etag", CRC64: 2},
+		},
+	}
+	if err := existing.save(cpPath); err != nil {
+		t.Fatal(err)
+	}
+
+	origCheckpointPath := checkpointPath
+	checkpointPath = cpPath
+	defer func() { checkpointPath = origCheckpointPath }()
+
+	up := &fakeUploader{listed: []backend.Part{
+		{Number: 1, ETag: "etag-1"},
+	}}
+
+	uploadID, cp, resumed, err := initiateOrResumeUpload(up, filePath, "obj.bin", stat, 5<<20, noneReporter{})
+	if err != nil {
+		t.Fatalf("initiateOrResumeUpload: %v", err)
+	}
+	if !resumed {
+		t.Fatal("expected the matching checkpoint to be resumed")
+	}
+	if uploadID != "resumed-upload-id" {
+		t.Errorf("uploadID = %q, want %q", uploadID, "resumed-upload-id")
+	}
+
+	done := cp.completedNumbers()
+	if len(done) != 1 {
+		t.Fatalf("expected only the part confirmed by ListParts to survive reconciliation, got %+v", done)
+	}
+	if _, ok := done[1]; !ok {
+		t.Errorf("part 1 (etag matches remote) should have survived reconciliation: %+v", done)
+	}
+	if _, ok := done[2]; ok {
+		t.Errorf("part 2 (etag differs from remote) should have been dropped by reconciliation: %+v", done)
+	}
+}
+
+func TestInitiateOrResumeUploadStartsFreshWhenFileChanged(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "f.bin")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpPath := filepath.Join(dir, "checkpoint.json")
+	stale := &uploadCheckpoint{
+		UploadID: "stale-upload-id",
+		FilePath: filePath,
+		FileSize: stat.Size() + 1,
+		ModTime:  stat.ModTime(),
+		PartSize: 5 << 20,
+	}
+	if err := stale.save(cpPath); err != nil {
+		t.Fatal(err)
+	}
+
+	origCheckpointPath := checkpointPath
+	checkpointPath = cpPath
+	defer func() { checkpointPath = origCheckpointPath }()
+
+	up := &fakeUploader{listErr: nil}
+
+	uploadID, _, resumed, err := initiateOrResumeUpload(up, filePath, "obj.bin", stat, 5<<20, noneReporter{})
+	if err != nil {
+		t.Fatalf("initiateOrResumeUpload: %v", err)
+	}
+	if resumed {
+		t.Fatal("a checkpoint for a changed file should not be resumed")
+	}
+	if uploadID != "new-upload-id" {
+		t.Errorf("uploadID = %q, want a freshly initiated upload", uploadID)
+	}
+}
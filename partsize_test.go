@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestParsePartSizeFlag(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"100MB", 100 << 20, false},
+		{"1GB", 1 << 30, false},
+		{"512KB", 512 << 10, false},
+		{"2TB", 2 << 40, false},
+		{"10B", 10, false},
+		{"0MB", 0, true},
+		{"-5MB", 0, true},
+		{"not-a-size", 0, true},
+		{"5XB", 0, true},
+	}
+
+	for _, c := range cases {
+		got, err := parsePartSizeFlag(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parsePartSizeFlag(%q): expected error, got %d", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePartSizeFlag(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parsePartSizeFlag(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestChoosePartSize(t *testing.T) {
+	if got := choosePartSize(100<<20, 10<<30); got != 100<<20 {
+		t.Errorf("explicit part size should win: got %d, want %d", got, 100<<20)
+	}
+
+	if got := choosePartSize(1<<20, 10<<30); got != minPartSize {
+		t.Errorf("explicit part size below minPartSize should clamp up: got %d, want %d", got, minPartSize)
+	}
+
+	if got := choosePartSize(10<<40, 10<<30); got != maxPartSize {
+		t.Errorf("explicit part size above maxPartSize should clamp down: got %d, want %d", got, maxPartSize)
+	}
+
+	if got := choosePartSize(0, 1<<20); got != minPartSize {
+		t.Errorf("adaptive size for a small file should clamp up to minPartSize: got %d, want %d", got, minPartSize)
+	}
+
+	fileSize := int64(maxParts) * (10 << 20)
+	if got := choosePartSize(0, fileSize); got < 10<<20 {
+		t.Errorf("adaptive size should keep the part count within maxParts: got %d for file size %d", got, fileSize)
+	}
+}
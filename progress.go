@@ -0,0 +1,528 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Accepted -progress values.
+const (
+	progressTTY  = "tty"
+	progressJSON = "json"
+	progressNone = "none"
+	progressProm = "prom"
+)
+
+// progressTickInterval bounds how often a part reports intermediate progress,
+// so redrawing a tty bar or emitting json lines doesn't dominate upload time.
+const progressTickInterval = 200 * time.Millisecond
+
+// progressEvent is one JSON line emitted by the json reporter, one per
+// progress update for a part.
+type progressEvent struct {
+	Ts          int64   `json:"ts"`
+	Part        int     `json:"part"`
+	Consumed    int64   `json:"consumed"`
+	Total       int64   `json:"total"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	ETASec      float64 `json:"eta_sec"`
+}
+
+// progressReporter is how the upload and copy paths surface progress. Which
+// implementation is wired up is chosen by -progress; see newProgressReporter.
+type progressReporter interface {
+	// start announces the overall job size, once totalParts/totalBytes are known.
+	start(totalParts int, totalBytes int64, parallel int)
+	// note reports a one-off textual event, e.g. "resuming upload ...".
+	note(msg string)
+	// partStarted announces that part number has begun, size bytes total.
+	partStarted(number int, size int64)
+	// partRetrying announces that part number is being retried from scratch
+	// after a failed attempt, so any byte/time bookkeeping keyed by partial
+	// progress from the previous attempt must be reset rather than accumulated.
+	partRetrying(number int, size int64)
+	// partSkipped announces that part number, size bytes, was already done
+	// before this run (found complete in a resumed checkpoint).
+	partSkipped(number int, size int64)
+	// partProgress reports that consumed of size bytes of part number have been sent.
+	partProgress(number int, consumed, size int64)
+	// partCompleted announces that part number finished, successfully if err is nil.
+	partCompleted(number int, size int64, err error)
+	// finish is called once the whole job has ended.
+	finish()
+	// close releases any resources the reporter holds, e.g. an HTTP server.
+	close() error
+}
+
+// newProgressReporter builds the progressReporter selected by -progress.
+// metricsAddr is only used, and required, when mode is "prom".
+func newProgressReporter(mode, metricsAddr string) (progressReporter, error) {
+	switch mode {
+	case "", progressTTY:
+		return newTTYReporter(), nil
+	case progressJSON:
+		return newJSONReporter(), nil
+	case progressNone:
+		return noneReporter{}, nil
+	case progressProm:
+		return newPromReporter(metricsAddr)
+	default:
+		return nil, fmt.Errorf("unknown -progress %q: expected tty, json, none or prom", mode)
+	}
+}
+
+// progressCountingReader wraps an io.Reader, invoking onProgress with the
+// cumulative byte count as it is read, throttled to progressTickInterval so
+// the reporter isn't driven on every chunk read by the underlying SDK.
+type progressCountingReader struct {
+	r          io.Reader
+	size       int64
+	consumed   int64
+	last       time.Time
+	onProgress func(consumed, size int64)
+}
+
+func (p *progressCountingReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	if n > 0 {
+		p.consumed += int64(n)
+		if now := time.Now(); err != nil || now.Sub(p.last) >= progressTickInterval {
+			p.last = now
+			p.onProgress(p.consumed, p.size)
+		}
+	}
+	return n, err
+}
+
+// noneReporter discards everything; selected by -progress=none.
+type noneReporter struct{}
+
+func (noneReporter) start(int, int64, int)           {}
+func (noneReporter) note(string)                     {}
+func (noneReporter) partStarted(int, int64)          {}
+func (noneReporter) partRetrying(int, int64)         {}
+func (noneReporter) partSkipped(int, int64)          {}
+func (noneReporter) partProgress(int, int64, int64)  {}
+func (noneReporter) partCompleted(int, int64, error) {}
+func (noneReporter) finish()                         {}
+func (noneReporter) close() error                    { return nil }
+
+// jsonReporter emits one JSON line per progress event, suitable for piping
+// into log pipelines. Textual notes are sent to stderr so stdout stays
+// machine-readable.
+type jsonReporter struct {
+	mu      sync.Mutex
+	started map[int]time.Time
+	last    map[int]int64
+	out     io.Writer
+}
+
+func newJSONReporter() *jsonReporter {
+	return &jsonReporter{started: map[int]time.Time{}, last: map[int]int64{}, out: os.Stdout}
+}
+
+func (r *jsonReporter) start(int, int64, int) {}
+
+func (r *jsonReporter) note(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+func (r *jsonReporter) partStarted(number int, size int64) {
+	r.mu.Lock()
+	r.started[number] = time.Now()
+	r.last[number] = 0
+	r.mu.Unlock()
+	r.emit(number, 0, size)
+}
+
+// partRetrying resets the rate/ETA clock and byte high-water mark for number,
+// so a retried attempt's progress isn't measured against the failed one's.
+func (r *jsonReporter) partRetrying(number int, size int64) {
+	r.mu.Lock()
+	r.started[number] = time.Now()
+	r.last[number] = 0
+	r.mu.Unlock()
+}
+
+func (r *jsonReporter) partSkipped(number int, size int64) {
+	r.emit(number, size, size)
+}
+
+func (r *jsonReporter) partProgress(number int, consumed, size int64) {
+	r.mu.Lock()
+	r.last[number] = consumed
+	r.mu.Unlock()
+	r.emit(number, consumed, size)
+}
+
+func (r *jsonReporter) partCompleted(number int, size int64, err error) {
+	consumed := size
+	if err != nil {
+		r.mu.Lock()
+		consumed = r.last[number]
+		r.mu.Unlock()
+	}
+	r.emit(number, consumed, size)
+
+	r.mu.Lock()
+	delete(r.started, number)
+	delete(r.last, number)
+	r.mu.Unlock()
+}
+
+func (r *jsonReporter) finish() {}
+
+func (r *jsonReporter) close() error { return nil }
+
+func (r *jsonReporter) emit(number int, consumed, size int64) {
+	r.mu.Lock()
+	started, ok := r.started[number]
+	r.mu.Unlock()
+
+	var rate, eta float64
+	if ok {
+		if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+			rate = float64(consumed) / elapsed
+		}
+		if rate > 0 && size > consumed {
+			eta = float64(size-consumed) / rate
+		}
+	}
+
+	data, err := json.Marshal(progressEvent{
+		Ts:          time.Now().Unix(),
+		Part:        number,
+		Consumed:    consumed,
+		Total:       size,
+		BytesPerSec: rate,
+		ETASec:      eta,
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(r.out, string(data))
+}
+
+// ttyPartState is the last-known progress of one in-flight part, as tracked
+// by ttyReporter.
+type ttyPartState struct {
+	size     int64
+	consumed int64
+}
+
+// ttyReporter redraws a block of progress bars in place using carriage
+// returns: a single overall bar normally, or, once -parallel makes more than
+// one part in flight at a time, a bar per in-flight part plus an overall bar.
+type ttyReporter struct {
+	mu         sync.Mutex
+	multiBar   bool
+	totalBytes int64
+	doneBytes  int64
+	parts      map[int]*ttyPartState
+	lines      int
+	out        io.Writer
+}
+
+func newTTYReporter() *ttyReporter {
+	return &ttyReporter{parts: map[int]*ttyPartState{}, out: os.Stdout}
+}
+
+func (r *ttyReporter) start(totalParts int, totalBytes int64, parallel int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.totalBytes = totalBytes
+	r.multiBar = parallel > 1
+}
+
+func (r *ttyReporter) note(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clearLocked()
+	fmt.Fprintln(r.out, msg)
+}
+
+func (r *ttyReporter) partStarted(number int, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parts[number] = &ttyPartState{size: size}
+	r.redrawLocked()
+}
+
+// partRetrying drops the bar's consumed progress back to zero for a fresh
+// retry attempt.
+func (r *ttyReporter) partRetrying(number int, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parts[number] = &ttyPartState{size: size}
+	r.redrawLocked()
+}
+
+func (r *ttyReporter) partSkipped(number int, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.doneBytes += size
+	r.redrawLocked()
+}
+
+func (r *ttyReporter) partProgress(number int, consumed, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.parts[number]; ok {
+		p.consumed = consumed
+	}
+	r.redrawLocked()
+}
+
+func (r *ttyReporter) partCompleted(number int, size int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		r.doneBytes += size
+	}
+	delete(r.parts, number)
+
+	if !r.multiBar {
+		r.clearLocked()
+		if err != nil {
+			fmt.Fprintf(r.out, "part %d failed: %v\n", number, err)
+		} else {
+			fmt.Fprintf(r.out, "uploaded part %d\n", number)
+		}
+		return
+	}
+
+	if err != nil {
+		r.clearLocked()
+		fmt.Fprintf(r.out, "part %d failed: %v\n", number, err)
+	}
+	r.redrawLocked()
+}
+
+func (r *ttyReporter) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clearLocked()
+}
+
+func (r *ttyReporter) close() error { return nil }
+
+// clearLocked erases the previously drawn progress block so the next write
+// starts from a clean line. Caller must hold r.mu.
+func (r *ttyReporter) clearLocked() {
+	for i := 0; i < r.lines; i++ {
+		fmt.Fprint(r.out, "\x1b[1A\x1b[2K")
+	}
+	r.lines = 0
+}
+
+// redrawLocked repaints the progress block. Caller must hold r.mu.
+func (r *ttyReporter) redrawLocked() {
+	r.clearLocked()
+
+	var lines []string
+	consumed := r.doneBytes
+	if r.multiBar {
+		numbers := make([]int, 0, len(r.parts))
+		for n := range r.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		for _, n := range numbers {
+			p := r.parts[n]
+			consumed += p.consumed
+			lines = append(lines, fmt.Sprintf("part %-4d %s", n, progressBar(p.consumed, p.size)))
+		}
+	} else {
+		for _, p := range r.parts {
+			consumed += p.consumed
+		}
+	}
+	lines = append(lines, fmt.Sprintf("overall    %s", progressBar(consumed, r.totalBytes)))
+
+	for _, line := range lines {
+		fmt.Fprintln(r.out, line)
+	}
+	r.lines = len(lines)
+}
+
+// progressBar renders a fixed-width "[####......]  42.0% (1.2MiB/3.0MiB)" bar.
+func progressBar(consumed, total int64) string {
+	const width = 30
+
+	var pct float64
+	if total > 0 {
+		pct = float64(consumed) / float64(total)
+	}
+	if pct > 1 {
+		pct = 1
+	}
+	filled := int(pct * width)
+
+	return fmt.Sprintf("[%s%s] %5.1f%% (%s/%s)",
+		strings.Repeat("#", filled), strings.Repeat(".", width-filled), pct*100,
+		humanBytes(consumed), humanBytes(total))
+}
+
+// humanBytes formats n using IEC binary units, e.g. 1536 -> "1.5KiB".
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// promReporter exposes Prometheus metrics over HTTP on -metrics-addr so
+// long-running batch jobs can be scraped and alerted on; selected by
+// -progress=prom.
+type promReporter struct {
+	server      *http.Server
+	bytesTotal  prometheus.Counter
+	partsFailed prometheus.Counter
+	inFlight    prometheus.Gauge
+	partLatency prometheus.Histogram
+
+	mu      sync.Mutex
+	started map[int]time.Time
+	last    map[int]int64
+}
+
+// newPromReporter starts an HTTP server on addr exposing /metrics. addr must
+// be non-empty; listening is best-effort and failures are logged rather than
+// fatal, since losing the metrics endpoint shouldn't abort the upload itself.
+func newPromReporter(addr string) (*promReporter, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("-metrics-addr is required when -progress=prom")
+	}
+
+	r := &promReporter{
+		started: map[int]time.Time{},
+		last:    map[int]int64{},
+		bytesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ossupload_bytes_uploaded_total",
+			Help: "Total number of bytes uploaded across all parts.",
+		}),
+		partsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ossupload_parts_failed_total",
+			Help: "Total number of parts that failed to upload after exhausting retries.",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ossupload_parts_inflight",
+			Help: "Number of parts currently being uploaded.",
+		}),
+		partLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ossupload_part_upload_duration_seconds",
+			Help:    "Latency of a single part upload, in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(r.bytesTotal, r.partsFailed, r.inFlight, r.partLatency)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	r.server = &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		if err := r.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Println("warning: metrics server stopped:", err)
+		}
+	}()
+	fmt.Printf("metrics endpoint listening on %s/metrics\n", addr)
+
+	return r, nil
+}
+
+func (r *promReporter) start(int, int64, int) {}
+
+func (r *promReporter) note(msg string) {
+	fmt.Println(msg)
+}
+
+func (r *promReporter) partStarted(number int, size int64) {
+	r.mu.Lock()
+	r.started[number] = time.Now()
+	r.last[number] = 0
+	r.mu.Unlock()
+	r.inFlight.Inc()
+}
+
+// partRetrying resets the latency clock and byte high-water mark for number
+// without touching inFlight, which is already accounted for by the in-flight
+// attempt this retry replaces. Without this, partProgress on the new attempt
+// would see a consumed smaller than the failed attempt's high-water mark,
+// skip the (negative) delta, but still drop last[number] down to it — so the
+// retry's later progress would re-add bytes already counted once before the
+// failure, permanently inflating bytesTotal.
+func (r *promReporter) partRetrying(number int, size int64) {
+	r.mu.Lock()
+	r.started[number] = time.Now()
+	r.last[number] = 0
+	r.mu.Unlock()
+}
+
+func (r *promReporter) partSkipped(number int, size int64) {
+	r.bytesTotal.Add(float64(size))
+}
+
+func (r *promReporter) partProgress(number int, consumed, size int64) {
+	r.mu.Lock()
+	delta := consumed - r.last[number]
+	r.last[number] = consumed
+	r.mu.Unlock()
+	if delta > 0 {
+		r.bytesTotal.Add(float64(delta))
+	}
+}
+
+func (r *promReporter) partCompleted(number int, size int64, err error) {
+	r.mu.Lock()
+	started, ok := r.started[number]
+	delta := size - r.last[number]
+	delete(r.started, number)
+	delete(r.last, number)
+	r.mu.Unlock()
+
+	r.inFlight.Dec()
+	if ok {
+		r.partLatency.Observe(time.Since(started).Seconds())
+	}
+
+	// Parts that never report partProgress (e.g. a server-side copy, which
+	// never reads bytes through the client) still count their full size here
+	// once they succeed.
+	if err == nil && delta > 0 {
+		r.bytesTotal.Add(float64(delta))
+	}
+
+	if err != nil {
+		r.partsFailed.Inc()
+		fmt.Printf("part %d failed: %v\n", number, err)
+		return
+	}
+	fmt.Printf("uploaded part %d\n", number)
+}
+
+func (r *promReporter) finish() {}
+
+func (r *promReporter) close() error {
+	return r.server.Close()
+}
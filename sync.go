@@ -0,0 +1,375 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+
+	"github.com/CareyWang/ossupload/internal/backend"
+)
+
+// syncFile is one local file discovered by the directory walk, along with the
+// object key it maps to in the bucket.
+type syncFile struct {
+	LocalPath string
+	Key       string
+	Size      int64
+}
+
+// syncStats tallies what a sync run did, for the summary line printed at the end.
+type syncStats struct {
+	mu       sync.Mutex
+	uploaded int
+	skipped  int
+	deleted  int
+	failed   int
+}
+
+func (s *syncStats) add(field *int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*field++
+}
+
+// runSync implements "ossupload sync", mirroring a local directory into a
+// bucket prefix: changed files are uploaded in parallel, unchanged files are
+// skipped based on size + checksum comparison against the remote object (via
+// backend.Uploader.Head), and, with -delete, remote objects absent locally
+// are removed. -delete currently requires the oss backend, since listing and
+// deleting objects isn't part of the backend.Uploader interface.
+func runSync(args []string) {
+	flagSet := flag.NewFlagSet("sync", flag.ExitOnError)
+	var (
+		src          string
+		syncBucket   string
+		prefix       string
+		syncBackend  string
+		syncEndpoint string
+		syncRegion   string
+		deleteRemote bool
+		dryRun       bool
+		include      string
+		exclude      string
+		syncParallel int
+	)
+	flagSet.StringVar(&src, "src", "", "local directory to mirror")
+	flagSet.StringVar(&syncBucket, "bucket", "", "bucket name")
+	flagSet.StringVar(&prefix, "prefix", "", "object key prefix for uploaded files")
+	flagSet.StringVar(&syncBackend, "backend", "oss", "storage backend: oss, s3, cos or obs (-delete requires oss)")
+	flagSet.StringVar(&syncEndpoint, "endpoint", "", "storage endpoint (for cos, the bucket's full access URL)")
+	flagSet.StringVar(&syncRegion, "region", "", "region, required by the s3 backend")
+	flagSet.BoolVar(&deleteRemote, "delete", false, "remove remote objects that no longer exist locally (oss backend only)")
+	flagSet.BoolVar(&dryRun, "dry-run", false, "print planned actions without uploading or deleting")
+	flagSet.StringVar(&include, "include", "", "comma-separated glob patterns; only matching files are synced")
+	flagSet.StringVar(&exclude, "exclude", "", "comma-separated glob patterns to skip")
+	flagSet.IntVar(&syncParallel, "parallel", 4, "number of files to sync concurrently")
+	flagSet.Parse(args)
+
+	if src == "" || syncBucket == "" {
+		fmt.Println("error: sync requires -src and -bucket")
+		os.Exit(-1)
+	}
+	if deleteRemote && syncBackend != "" && syncBackend != "oss" {
+		fmt.Println("error: -delete requires -backend=oss")
+		os.Exit(-1)
+	}
+	if syncParallel < 1 {
+		syncParallel = 1
+	}
+	// multipartUpload reads the worker count for large files off this global.
+	parallel = syncParallel
+
+	// newUploader reads its backend selection from these shared globals, so
+	// sync sets them from its own flags before building the Uploader used for
+	// Head, PutObject and multipart uploads.
+	backendName = syncBackend
+	endpoint = syncEndpoint
+	region = syncRegion
+	bucketName = syncBucket
+
+	up, err := newUploader()
+	if err != nil {
+		fmt.Println("error: ", err)
+		os.Exit(-1)
+	}
+
+	var bucket *oss.Bucket
+	if deleteRemote {
+		client, err := oss.New(syncEndpoint, accessKeyID, accessKeySecret)
+		if err != nil {
+			fmt.Println("error: ", err)
+			os.Exit(-1)
+		}
+		bucket, err = client.Bucket(syncBucket)
+		if err != nil {
+			fmt.Println("error: ", err)
+			os.Exit(-1)
+		}
+	}
+
+	includePatterns := splitPatterns(include)
+	excludePatterns := splitPatterns(exclude)
+
+	files, err := walkSyncDir(src, prefix, includePatterns, excludePatterns)
+	if err != nil {
+		fmt.Println("error: ", err)
+		os.Exit(-1)
+	}
+	fmt.Printf("sync: %d local files match, uploading with %d workers\n", len(files), syncParallel)
+
+	stats := &syncStats{}
+	jobs := make(chan syncFile, len(files))
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < syncParallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				syncOneFile(up, f, dryRun, stats)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if deleteRemote {
+		// -delete must only remove objects whose local file is truly gone, not
+		// ones merely excluded from this run's upload list by -include/-exclude.
+		// So the local-existence set is built from an unfiltered walk, separate
+		// from the (possibly filtered) upload list above.
+		allFiles, err := walkSyncDir(src, prefix, nil, nil)
+		if err != nil {
+			fmt.Println("error: ", err)
+			os.Exit(-1)
+		}
+		local := make(map[string]bool, len(allFiles))
+		for _, f := range allFiles {
+			local[f.Key] = true
+		}
+		if err := deleteStaleObjects(bucket, prefix, local, dryRun, stats); err != nil {
+			fmt.Println("error: ", err)
+			os.Exit(-1)
+		}
+	}
+
+	fmt.Printf("sync complete: %d uploaded, %d skipped, %d deleted, %d failed\n",
+		stats.uploaded, stats.skipped, stats.deleted, stats.failed)
+	if stats.failed > 0 {
+		os.Exit(-1)
+	}
+}
+
+// splitPatterns turns a comma-separated pattern list into a slice, dropping
+// empty entries.
+func splitPatterns(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// matchesAny reports whether name matches any of the glob patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walkSyncDir walks src and returns the local files to sync, each paired with
+// the object key it maps to under prefix.
+func walkSyncDir(src, prefix string, include, exclude []string) ([]syncFile, error) {
+	var files []syncFile
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if len(include) > 0 && !matchesAny(include, rel) {
+			return nil
+		}
+		if matchesAny(exclude, rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, syncFile{
+			LocalPath: p,
+			Key:       path.Join(prefix, rel),
+			Size:      info.Size(),
+		})
+		return nil
+	})
+	return files, err
+}
+
+// syncOneFile uploads a single file if it differs from the remote object,
+// skipping it when the size and checksum already match.
+func syncOneFile(up backend.Uploader, f syncFile, dryRun bool, stats *syncStats) {
+	unchanged, err := remoteMatches(up, f)
+	if err != nil {
+		fmt.Printf("sync: %s: failed to check remote object: %v\n", f.Key, err)
+		stats.add(&stats.failed)
+		return
+	}
+	if unchanged {
+		fmt.Printf("skip %s (unchanged)\n", f.Key)
+		stats.add(&stats.skipped)
+		return
+	}
+
+	if dryRun {
+		fmt.Printf("would upload %s -> %s\n", f.LocalPath, f.Key)
+		stats.add(&stats.uploaded)
+		return
+	}
+
+	fmt.Printf("uploading %s -> %s\n", f.LocalPath, f.Key)
+	if f.Size > simpleUploadThreshold {
+		err = multipartUpload(up, f.LocalPath, f.Key, 0, noneReporter{})
+	} else {
+		err = simpleUpload(up, f.LocalPath, f.Key)
+	}
+	if err != nil {
+		fmt.Printf("sync: %s: upload failed: %v\n", f.Key, err)
+		stats.add(&stats.failed)
+		return
+	}
+	stats.add(&stats.uploaded)
+}
+
+// remoteMatches reports whether the remote object's size and checksum already
+// match the local file, in which case the upload can be skipped. It goes
+// through backend.Uploader.Head so the comparison works against any of the
+// supported storage providers, not just OSS. When the backend's HeadInfo
+// doesn't carry a CRC64 (everything but OSS), it falls back to comparing the
+// local file's MD5 against the remote ETag; that only matches objects that
+// were themselves uploaded as a single PutObject (a multipart ETag isn't a
+// plain MD5), so non-OSS backends may re-upload unchanged multipart-sized
+// files unnecessarily rather than risk a false "unchanged".
+func remoteMatches(up backend.Uploader, f syncFile) (bool, error) {
+	info, err := up.Head(f.Key)
+	if err != nil {
+		return false, err
+	}
+	if !info.Exists || info.Size != f.Size {
+		return false, nil
+	}
+
+	if info.CRC64 != 0 {
+		localCRC, err := localFileCRC64(f.LocalPath)
+		if err != nil {
+			return false, err
+		}
+		return localCRC == info.CRC64, nil
+	}
+
+	localMD5, err := localFileMD5(f.LocalPath)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(strings.Trim(info.ETag, `"`), localMD5), nil
+}
+
+// localFileCRC64 computes the CRC64 of a local file using the same polynomial
+// OSS reports in x-oss-hash-crc64ecma.
+func localFileCRC64(localPath string) (uint64, error) {
+	fd, err := os.Open(localPath)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+
+	h := crc64.New(ossCRC64Table)
+	if _, err := io.Copy(h, fd); err != nil {
+		return 0, err
+	}
+	return h.Sum64(), nil
+}
+
+// localFileMD5 computes the hex-encoded MD5 of a local file, for comparison
+// against a backend's plain-PutObject ETag.
+func localFileMD5(localPath string) (string, error) {
+	fd, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// deleteStaleObjects removes every object under prefix that is not present in
+// local, honoring dry-run.
+func deleteStaleObjects(bucket *oss.Bucket, prefix string, local map[string]bool, dryRun bool, stats *syncStats) error {
+	marker := ""
+	for {
+		result, err := bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker))
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range result.Objects {
+			if local[obj.Key] {
+				continue
+			}
+			if dryRun {
+				fmt.Printf("would delete %s\n", obj.Key)
+				stats.add(&stats.deleted)
+				continue
+			}
+			fmt.Printf("deleting %s\n", obj.Key)
+			if err := bucket.DeleteObject(obj.Key); err != nil {
+				fmt.Printf("sync: %s: delete failed: %v\n", obj.Key, err)
+				stats.add(&stats.failed)
+				continue
+			}
+			stats.add(&stats.deleted)
+		}
+
+		if !result.IsTruncated {
+			return nil
+		}
+		marker = result.NextMarker
+	}
+}